@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	crv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultImageConfigCacheSize is the default number of resolved image
+	// configs kept in the in-process cache.
+	DefaultImageConfigCacheSize = 256
+	// DefaultImageConfigCacheTTL is the default lifetime of a cached entry
+	// before it must be re-resolved from the registry.
+	DefaultImageConfigCacheTTL = 10 * time.Minute
+)
+
+var (
+	imageConfigCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "katib_controller_pod_webhook_image_config_cache_hits_total",
+		Help: "Number of times the resolved image config was served from cache during pod mutation.",
+	})
+	imageConfigCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "katib_controller_pod_webhook_image_config_cache_misses_total",
+		Help: "Number of times the resolved image config was not found in cache and had to be looked up.",
+	})
+	imageConfigCacheRegistryErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "katib_controller_pod_webhook_image_config_registry_errors_total",
+		Help: "Number of registry round-trips for image config resolution that failed.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(imageConfigCacheHits, imageConfigCacheMisses, imageConfigCacheRegistryErrors)
+}
+
+// imageConfigCacheEntry is a single cached crv1.ConfigFile along with its
+// expiration time.
+type imageConfigCacheEntry struct {
+	key       string
+	cfg       *crv1.ConfigFile
+	expiresAt time.Time
+}
+
+// imageConfigCache is a bounded, TTL-based LRU cache of image configs keyed
+// by the image reference, the identity of the imagePullSecrets used to
+// resolve it, and the resolved target platform. It is safe for concurrent
+// use by multiple admission webhook goroutines.
+type imageConfigCache struct {
+	mu       sync.Mutex
+	size     int
+	ttl      time.Duration
+	ll       *list.List
+	elements map[string]*list.Element
+}
+
+func newImageConfigCache(size int, ttl time.Duration) *imageConfigCache {
+	if size <= 0 {
+		size = DefaultImageConfigCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultImageConfigCacheTTL
+	}
+	return &imageConfigCache{
+		size:     size,
+		ttl:      ttl,
+		ll:       list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// imageConfigCacheInstance is the process-wide cache used by
+// getRemoteImageConfig. It uses DefaultImageConfigCacheSize and
+// DefaultImageConfigCacheTTL until SetImageConfigCacheOptions is called;
+// this package defines no command-line flags for either setting.
+var imageConfigCacheInstance = newImageConfigCache(DefaultImageConfigCacheSize, DefaultImageConfigCacheTTL)
+
+// SetImageConfigCacheOptions reconfigures the process-wide image config
+// cache used by the pod webhook, replacing it with an empty cache of the
+// given size and TTL. Non-positive values select the defaults.
+func SetImageConfigCacheOptions(size int, ttl time.Duration) {
+	imageConfigCacheInstance = newImageConfigCache(size, ttl)
+}
+
+func (c *imageConfigCache) get(key string) (*crv1.ConfigFile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.elements[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*imageConfigCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(e)
+		delete(c.elements, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return entry.cfg, true
+}
+
+func (c *imageConfigCache) add(key string, cfg *crv1.ConfigFile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.elements[key]; ok {
+		e.Value.(*imageConfigCacheEntry).cfg = cfg
+		e.Value.(*imageConfigCacheEntry).expiresAt = time.Now().Add(c.ttl)
+		c.ll.MoveToFront(e)
+		return
+	}
+
+	e := c.ll.PushFront(&imageConfigCacheEntry{
+		key:       key,
+		cfg:       cfg,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	c.elements[key] = e
+
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.elements, oldest.Value.(*imageConfigCacheEntry).key)
+	}
+}
+
+// getRemoteImageConfig returns the crv1.ConfigFile for the given container's
+// image, serving it from the in-process cache when possible. The cache key
+// is derived from the image reference, the pod's imagePullSecrets identity
+// and its resolved target platform, so that two pods pulling the same image
+// with different credentials, or the same multi-arch image for different
+// architectures, are never conflated.
+func getRemoteImageConfig(pod *v1.Pod, namespace string, loc containerLocation) (*crv1.ConfigFile, error) {
+	c := *loc.get(pod)
+
+	key, err := imageConfigCacheKey(pod, namespace, c.Image)
+	if err != nil {
+		imageConfigCacheRegistryErrors.Inc()
+		return nil, err
+	}
+
+	if cfg, ok := imageConfigCacheInstance.get(key); ok {
+		imageConfigCacheHits.Inc()
+		return cfg, nil
+	}
+	imageConfigCacheMisses.Inc()
+
+	img, err := getRemoteImage(pod, namespace, loc)
+	if err != nil {
+		imageConfigCacheRegistryErrors.Inc()
+		return nil, err
+	}
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		imageConfigCacheRegistryErrors.Inc()
+		return nil, fmt.Errorf("Failed to get config for image %q: %v", c.Image, err)
+	}
+
+	imageConfigCacheInstance.add(key, cfg)
+	return cfg, nil
+}
+
+// imageConfigCacheKey builds the cache key for image without touching the
+// network: resolving the real manifest/config digest costs the same
+// registry round-trip (HEAD counts against pull-rate limits the same as GET
+// on most registries, including Docker Hub) that this cache exists to
+// avoid, so a cache hit would otherwise still pay for a registry call every
+// time. Instead the key is the raw image reference plus the pod's
+// imagePullSecrets identity and its resolved target platform (so that the
+// same multi-arch image resolved for two different architectures, e.g. the
+// chunk0-1 index-walking case, never shares an entry), and staleness is
+// bounded by the cache TTL rather than detected via digest change.
+func imageConfigCacheKey(pod *v1.Pod, namespace, image string) (string, error) {
+	if _, err := name.ParseReference(image, name.WeakValidation); err != nil {
+		return "", fmt.Errorf("Failed to parse image %q: %v", image, err)
+	}
+
+	imagePullSecrets := []string{}
+	for _, s := range pod.Spec.ImagePullSecrets {
+		imagePullSecrets = append(imagePullSecrets, s.Name)
+	}
+
+	os, arch := podPlatform(pod)
+	return fmt.Sprintf("%s|%s|%s|%s/%s", namespace, image, strings.Join(imagePullSecrets, ","), os, arch), nil
+}
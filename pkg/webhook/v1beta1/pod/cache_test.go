@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"testing"
+	"time"
+
+	crv1 "github.com/google/go-containerregistry/pkg/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestImageConfigCacheGetAdd(t *testing.T) {
+	c := newImageConfigCache(2, time.Minute)
+
+	if _, ok := c.get("missing"); ok {
+		t.Fatalf("get() on empty cache returned ok=true")
+	}
+
+	cfg := &crv1.ConfigFile{}
+	c.add("a", cfg)
+
+	got, ok := c.get("a")
+	if !ok || got != cfg {
+		t.Fatalf("get(%q) = (%v, %v), want (%v, true)", "a", got, ok, cfg)
+	}
+}
+
+func TestImageConfigCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newImageConfigCache(2, time.Minute)
+
+	c.add("a", &crv1.ConfigFile{})
+	c.add("b", &crv1.ConfigFile{})
+	// Touch "a" so "b" becomes the least recently used entry.
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("get(%q) = false, want true", "a")
+	}
+	c.add("c", &crv1.ConfigFile{})
+
+	if _, ok := c.get("b"); ok {
+		t.Fatalf("get(%q) = true after eviction, want false", "b")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("get(%q) = false, want true (should not have been evicted)", "a")
+	}
+	if _, ok := c.get("c"); !ok {
+		t.Fatalf("get(%q) = false, want true", "c")
+	}
+}
+
+func TestImageConfigCacheTTLExpiry(t *testing.T) {
+	c := newImageConfigCache(2, time.Minute)
+	c.add("a", &crv1.ConfigFile{})
+
+	// Force the entry to look expired without sleeping in the test.
+	c.elements["a"].Value.(*imageConfigCacheEntry).expiresAt = time.Now().Add(-time.Second)
+
+	if _, ok := c.get("a"); ok {
+		t.Fatalf("get(%q) = true for an expired entry, want false", "a")
+	}
+	if _, ok := c.elements["a"]; ok {
+		t.Fatalf("expired entry was not evicted from the cache")
+	}
+}
+
+func TestImageConfigCacheKeyDiffersByPlatform(t *testing.T) {
+	amd64Pod := &v1.Pod{
+		Spec: v1.PodSpec{NodeSelector: map[string]string{v1.LabelArchStable: "amd64"}},
+	}
+	arm64Pod := &v1.Pod{
+		Spec: v1.PodSpec{NodeSelector: map[string]string{v1.LabelArchStable: "arm64"}},
+	}
+
+	amd64Key, err := imageConfigCacheKey(amd64Pod, "default", "python:3.11")
+	if err != nil {
+		t.Fatalf("imageConfigCacheKey() returned error: %v", err)
+	}
+	arm64Key, err := imageConfigCacheKey(arm64Pod, "default", "python:3.11")
+	if err != nil {
+		t.Fatalf("imageConfigCacheKey() returned error: %v", err)
+	}
+
+	if amd64Key == arm64Key {
+		t.Fatalf("imageConfigCacheKey() returned the same key %q for different platforms", amd64Key)
+	}
+}
+
+func TestImageConfigCacheKeyInvalidImage(t *testing.T) {
+	if _, err := imageConfigCacheKey(&v1.Pod{}, "default", ""); err == nil {
+		t.Fatal("imageConfigCacheKey() returned no error for an invalid image reference")
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func criDialer(ctx context.Context, addr string) (net.Conn, error) {
+	d := net.Dialer{}
+	return d.DialContext(ctx, "unix", addr)
+}
+
+// CommandResolverAnnotation records which CommandResolver produced the
+// entrypoint/cmd injected into the training container, for debuggability.
+const CommandResolverAnnotation = "trial.kubeflow.org/command-resolver"
+
+// ResolvedCommand is the container image's default entrypoint/cmd, as
+// produced by a CommandResolver.
+type ResolvedCommand struct {
+	Entrypoint []string
+	Cmd        []string
+}
+
+// CommandResolver infers the entrypoint/cmd of a container's image when the
+// trial pod spec doesn't set Command/Args explicitly. wrapWorkerContainer
+// tries the configured resolvers in order and uses the first one that
+// succeeds, so that clusters that can't reach the image registry (air-gapped
+// clusters, private registries without credentials in imagePullSecrets) can
+// still mutate trial pods.
+type CommandResolver interface {
+	// Name identifies the resolver, and is recorded in
+	// CommandResolverAnnotation when it succeeds.
+	Name() string
+	// Resolve returns the image's default entrypoint/cmd for the container
+	// at loc. It returns an error if this resolver cannot resolve the
+	// image, letting the caller fall through to the next resolver.
+	Resolve(pod *v1.Pod, namespace string, loc containerLocation) (*ResolvedCommand, error)
+}
+
+var (
+	commandResolversMu sync.RWMutex
+	// commandResolvers defaults to the pre-existing behavior: always hit
+	// the remote registry. The CRI and ConfigMap resolvers are only tried
+	// once they are installed through SetCommandResolvers; this package
+	// defines no command-line flag that selects them.
+	commandResolvers = []CommandResolver{&remoteRegistryCommandResolver{}}
+)
+
+// SetCommandResolvers replaces the ordered list of CommandResolvers tried by
+// getContainerCommand. Order matters: the first resolver to succeed wins.
+func SetCommandResolvers(resolvers ...CommandResolver) {
+	commandResolversMu.Lock()
+	defer commandResolversMu.Unlock()
+	commandResolvers = resolvers
+}
+
+// resolveContainerCommand tries the configured CommandResolvers, in order,
+// and annotates the pod with the name of whichever one succeeded.
+func resolveContainerCommand(pod *v1.Pod, namespace string, loc containerLocation) (*ResolvedCommand, error) {
+	commandResolversMu.RLock()
+	resolvers := commandResolvers
+	commandResolversMu.RUnlock()
+
+	var lastErr error
+	for _, r := range resolvers {
+		resolved, err := r.Resolve(pod, namespace, loc)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[CommandResolverAnnotation] = r.Name()
+		return resolved, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no command resolvers configured")
+	}
+	return nil, fmt.Errorf("Failed to resolve command for image %q: %v", loc.get(pod).Image, lastErr)
+}
+
+// remoteRegistryCommandResolver is the original strategy: download the
+// image config from the registry (through the shared, cached
+// getRemoteImageConfig) and read Entrypoint/Cmd off it.
+type remoteRegistryCommandResolver struct{}
+
+func (r *remoteRegistryCommandResolver) Name() string { return "remote-registry" }
+
+func (r *remoteRegistryCommandResolver) Resolve(pod *v1.Pod, namespace string, loc containerLocation) (*ResolvedCommand, error) {
+	cfg, err := getRemoteImageConfig(pod, namespace, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedCommand{Entrypoint: cfg.Config.Entrypoint, Cmd: cfg.Config.Cmd}, nil
+}
+
+// criCommandResolver looks up the image's OCI config from the local node's
+// image store over the CRI socket, for images that have already been
+// pulled. This avoids a registry round-trip entirely and works in
+// air-gapped clusters or with private registries whose credentials aren't
+// present in imagePullSecrets, at the cost of only working for images
+// already present on the node running admission.
+type criCommandResolver struct {
+	socketPath  string
+	dialTimeout time.Duration
+}
+
+// NewCRICommandResolver returns a CommandResolver that queries the CRI
+// image service at socketPath (e.g. "/run/containerd/containerd.sock") for
+// an already-pulled image's config.
+func NewCRICommandResolver(socketPath string) CommandResolver {
+	return &criCommandResolver{socketPath: socketPath, dialTimeout: 5 * time.Second}
+}
+
+func (r *criCommandResolver) Name() string { return "cri" }
+
+func (r *criCommandResolver) Resolve(pod *v1.Pod, namespace string, loc containerLocation) (*ResolvedCommand, error) {
+	image := loc.get(pod).Image
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.dialTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, r.socketPath, grpc.WithInsecure(), grpc.WithContextDialer(criDialer))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to dial CRI socket %q: %v", r.socketPath, err)
+	}
+	defer conn.Close()
+
+	client := runtimeapi.NewImageServiceClient(conn)
+	status, err := client.ImageStatus(ctx, &runtimeapi.ImageStatusRequest{
+		Image: &runtimeapi.ImageSpec{Image: image},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query CRI image status for %q: %v", image, err)
+	}
+	if status.GetImage() == nil {
+		return nil, fmt.Errorf("Image %q is not present in the local CRI image store", image)
+	}
+
+	spec := status.GetImage().GetSpec()
+	if spec == nil {
+		return nil, fmt.Errorf("CRI image store has no OCI config for %q", image)
+	}
+	return &ResolvedCommand{Entrypoint: spec.Entrypoint, Cmd: spec.Cmd}, nil
+}
+
+// configMapCommandResolver resolves images that operators have pre-declared
+// an entrypoint/cmd for, typically loaded from a ConfigMap and updated
+// through SetCommands.
+type configMapCommandResolver struct {
+	mu       sync.RWMutex
+	commands map[string]ResolvedCommand
+}
+
+// NewConfigMapCommandResolver returns a CommandResolver backed by a static
+// image -> {entrypoint, cmd} mapping.
+func NewConfigMapCommandResolver(commands map[string]ResolvedCommand) *configMapCommandResolver {
+	return &configMapCommandResolver{commands: commands}
+}
+
+// SetCommands replaces the image -> command mapping, so that it can be
+// reloaded when the backing ConfigMap changes.
+func (r *configMapCommandResolver) SetCommands(commands map[string]ResolvedCommand) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = commands
+}
+
+func (r *configMapCommandResolver) Name() string { return "configmap" }
+
+func (r *configMapCommandResolver) Resolve(pod *v1.Pod, namespace string, loc containerLocation) (*ResolvedCommand, error) {
+	image := loc.get(pod).Image
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resolved, ok := r.commands[image]
+	if !ok {
+		return nil, fmt.Errorf("No ConfigMap command mapping for image %q", image)
+	}
+	return &resolved, nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	v1 "k8s.io/api/core/v1"
+)
+
+// TrustMode controls how a failed signature verification affects admission.
+type TrustMode string
+
+const (
+	// TrustModeEnforce rejects admission of trial pods whose training image
+	// cannot be verified against the configured policy.
+	TrustModeEnforce TrustMode = "enforce"
+	// TrustModeWarn only annotates the pod when verification fails, allowing
+	// operators to roll out a TrustPolicy gradually before enforcing it.
+	TrustModeWarn TrustMode = "warn"
+)
+
+// TrustPolicyWarningAnnotation is set on a trial pod when TrustModeWarn is
+// active and the training image failed signature verification.
+const TrustPolicyWarningAnnotation = "trial.kubeflow.org/image-trust-warning"
+
+// TrustPolicyRule describes the cosign public keys (and optional Rekor
+// transparency-log requirement) that images matching a registry scope must
+// be signed with.
+type TrustPolicyRule struct {
+	// RegistryScope is a registry/repository prefix, e.g.
+	// "docker.io/kubeflowkatib/" or "*" to match any image.
+	RegistryScope string `json:"registryScope"`
+	// CosignPublicKeys are PEM-encoded cosign public keys; an image is
+	// trusted if its signature verifies against any one of them.
+	CosignPublicKeys []string `json:"cosignPublicKeys"`
+	// RequireRekor mandates that the signature also have a corresponding
+	// Rekor transparency-log entry.
+	RequireRekor bool `json:"requireRekor,omitempty"`
+}
+
+// TrustPolicy is the set of rules the pod webhook enforces (or warns about)
+// before mutating a trial pod whose training image carries a
+// metrics-collector sidecar. It is a plain JSON-tagged struct rather than a
+// CRD so that it can be loaded from a ConfigMap. The policy in effect is
+// whatever was last passed to SetTrustPolicy; this package does not watch a
+// ConfigMap itself.
+type TrustPolicy struct {
+	Mode  TrustMode         `json:"mode"`
+	Rules []TrustPolicyRule `json:"rules"`
+}
+
+var (
+	trustPolicyMu sync.RWMutex
+	trustPolicy   *TrustPolicy
+)
+
+// SetTrustPolicy installs the active TrustPolicy used by the pod webhook. A
+// nil policy disables signature verification entirely, which is also the
+// default when no policy has been configured.
+func SetTrustPolicy(policy *TrustPolicy) {
+	trustPolicyMu.Lock()
+	defer trustPolicyMu.Unlock()
+	trustPolicy = policy
+}
+
+func getTrustPolicy() *TrustPolicy {
+	trustPolicyMu.RLock()
+	defer trustPolicyMu.RUnlock()
+	return trustPolicy
+}
+
+// rulesForImage returns the TrustPolicyRule(s) whose RegistryScope matches
+// image, in the order they appear in the policy.
+func (p *TrustPolicy) rulesForImage(image string) []TrustPolicyRule {
+	matches := []TrustPolicyRule{}
+	for _, r := range p.Rules {
+		if r.RegistryScope == "*" || strings.HasPrefix(image, r.RegistryScope) {
+			matches = append(matches, r)
+		}
+	}
+	return matches
+}
+
+// verifyImageSignature checks the image's cosign signature (stored, per
+// sigstore convention, as the "sha256-<digest>.sig" tag) against every
+// public key allowed for its registry scope, succeeding if any one matches.
+// It returns an error describing why verification failed so the webhook can
+// surface a clear admission error or warning annotation.
+func verifyImageSignature(ref name.Reference, rules []TrustPolicyRule) error {
+	var lastErr error
+	for _, rule := range rules {
+		for _, pubKeyPEM := range rule.CosignPublicKeys {
+			pubKey, err := cryptoutils.UnmarshalPEMToPublicKey([]byte(pubKeyPEM))
+			if err != nil {
+				lastErr = fmt.Errorf("Failed to parse cosign public key for scope %q: %v", rule.RegistryScope, err)
+				continue
+			}
+			verifier, err := signature.LoadVerifier(pubKey, crypto.SHA256)
+			if err != nil {
+				lastErr = fmt.Errorf("Failed to load cosign verifier for scope %q: %v", rule.RegistryScope, err)
+				continue
+			}
+			_, _, err = cosign.VerifyImageSignatures(context.Background(), ref, &cosign.CheckOpts{
+				SigVerifier: verifier,
+				RequireTlog: rule.RequireRekor,
+			})
+			if err == nil {
+				return nil
+			}
+			lastErr = fmt.Errorf("Signature verification failed for %q against scope %q: %v", ref, rule.RegistryScope, err)
+		}
+	}
+	if lastErr == nil {
+		return fmt.Errorf("No trusted cosign public keys configured for image %q", ref)
+	}
+	return lastErr
+}
+
+// enforceTrustPolicy verifies the training container's image against the
+// active TrustPolicy, if one is configured. In TrustModeEnforce a failure is
+// returned as an error that aborts admission; in TrustModeWarn the pod is
+// annotated instead and mutation proceeds.
+func enforceTrustPolicy(pod *v1.Pod, loc containerLocation) error {
+	policy := getTrustPolicy()
+	if policy == nil {
+		return nil
+	}
+
+	image := loc.get(pod).Image
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return fmt.Errorf("Failed to parse image %q: %v", image, err)
+	}
+
+	rules := policy.rulesForImage(image)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	verifyErr := verifyImageSignature(ref, rules)
+	if verifyErr == nil {
+		return nil
+	}
+
+	if policy.Mode == TrustModeWarn {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[TrustPolicyWarningAnnotation] = verifyErr.Error()
+		return nil
+	}
+
+	return fmt.Errorf("Rejecting trial pod: %v", verifyErr)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import "testing"
+
+func TestTrustPolicyRulesForImage(t *testing.T) {
+	policy := &TrustPolicy{
+		Rules: []TrustPolicyRule{
+			{RegistryScope: "docker.io/kubeflowkatib/"},
+			{RegistryScope: "gcr.io/my-project/"},
+			{RegistryScope: "*"},
+		},
+	}
+
+	testCases := map[string]struct {
+		image     string
+		wantCount int
+	}{
+		"matches a specific scope and the wildcard": {
+			image:     "docker.io/kubeflowkatib/mnist:latest",
+			wantCount: 2,
+		},
+		"matches only the wildcard": {
+			image:     "quay.io/someone/else:latest",
+			wantCount: 1,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rules := policy.rulesForImage(tc.image)
+			if len(rules) != tc.wantCount {
+				t.Errorf("rulesForImage(%q) returned %d rules, want %d", tc.image, len(rules), tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestTrustPolicyRulesForImageNoMatch(t *testing.T) {
+	policy := &TrustPolicy{
+		Rules: []TrustPolicyRule{
+			{RegistryScope: "docker.io/kubeflowkatib/"},
+		},
+	}
+
+	rules := policy.rulesForImage("quay.io/someone/else:latest")
+	if len(rules) != 0 {
+		t.Errorf("rulesForImage() returned %d rules, want 0", len(rules))
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/authn"
@@ -76,13 +77,67 @@ func getLabel(pod *v1.Pod, targetLabel string) (string, error) {
 	return "", errors.New("Label " + targetLabel + " not found.")
 }
 
-func getRemoteImage(pod *v1.Pod, namespace string, containerIndex int) (crv1.Image, error) {
-	// verify the image name, then download the remote config file
-	c := pod.Spec.Containers[containerIndex]
-	ref, err := name.ParseReference(c.Image, name.WeakValidation)
+// containerLocation identifies a container within either the regular or the
+// init container list of a pod, so that training-container resolution and
+// metrics-collector wrapping can treat both uniformly.
+type containerLocation struct {
+	index int
+	init  bool
+}
+
+func (loc containerLocation) get(pod *v1.Pod) *v1.Container {
+	if loc.init {
+		return &pod.Spec.InitContainers[loc.index]
+	}
+	return &pod.Spec.Containers[loc.index]
+}
+
+// findPrimaryContainer resolves the trial's primary training container,
+// looking it up by name in both pod.Spec.Containers and
+// pod.Spec.InitContainers when Trial.Spec.PrimaryContainerName is set (to
+// support native sidecar / init-container training patterns), and falling
+// back to jobProvider.IsTrainingContainer over both container lists
+// otherwise, so an auto-detected trainer running as an init container is
+// recognized the same as one running as a regular container.
+func findPrimaryContainer(pod *v1.Pod, jobKind string, trial *trialsv1beta1.Trial) (containerLocation, error) {
+	if trial.Spec.PrimaryContainerName != "" {
+		for i, c := range pod.Spec.Containers {
+			if c.Name == trial.Spec.PrimaryContainerName {
+				return containerLocation{index: i}, nil
+			}
+		}
+		for i, c := range pod.Spec.InitContainers {
+			if c.Name == trial.Spec.PrimaryContainerName {
+				return containerLocation{index: i, init: true}, nil
+			}
+		}
+		return containerLocation{}, fmt.Errorf("Unable to find primary container %v in mutated pod containers %v",
+			trial.Spec.PrimaryContainerName, pod.Spec.Containers)
+		// TODO (andreyvelich): This can be deleted after switch to custom CRD
+	}
+
+	jobProvider, err := jobv1beta1.New(jobKind)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to parse image %q: %v", c.Image, err)
+		return containerLocation{}, err
+	}
+	for i, c := range pod.Spec.Containers {
+		if jobProvider.IsTrainingContainer(i, c) {
+			return containerLocation{index: i}, nil
+		}
 	}
+	for i, c := range pod.Spec.InitContainers {
+		if jobProvider.IsTrainingContainer(i, c) {
+			return containerLocation{index: i, init: true}, nil
+		}
+	}
+	return containerLocation{}, fmt.Errorf("Unable to find primary container %v in mutated pod containers %v",
+		trial.Spec.PrimaryContainerName, pod.Spec.Containers)
+}
+
+// podKeychain builds the authn.Keychain used to authenticate registry calls
+// made on behalf of pod: the in-cluster service account identity plus
+// whatever imagePullSecrets the pod carries.
+func podKeychain(pod *v1.Pod, namespace string) (authn.Keychain, error) {
 	imagePullSecrets := []string{}
 	for _, s := range pod.Spec.ImagePullSecrets {
 		imagePullSecrets = append(imagePullSecrets, s.Name)
@@ -95,9 +150,31 @@ func getRemoteImage(pod *v1.Pod, namespace string, containerIndex int) (crv1.Ima
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create k8schain: %v", err)
 	}
+	return authn.NewMultiKeychain(kc), nil
+}
+
+func getRemoteImage(pod *v1.Pod, namespace string, loc containerLocation) (crv1.Image, error) {
+	// verify the image name, then fetch the image from the registry
+	c := *loc.get(pod)
+	ref, err := name.ParseReference(c.Image, name.WeakValidation)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse image %q: %v", c.Image, err)
+	}
+	mkc, err := podKeychain(pod, namespace)
+	if err != nil {
+		return nil, err
+	}
 
-	mkc := authn.NewMultiKeychain(kc)
-	img, err := remote.Image(ref, remote.WithAuthFromKeychain(mkc))
+	desc, err := remote.Get(ref, remote.WithAuthFromKeychain(mkc))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get container image %q descriptor from registry: %v", c.Image, err)
+	}
+
+	if desc.MediaType.IsIndex() {
+		return resolveImageFromIndex(desc, pod, c.Image)
+	}
+
+	img, err := desc.Image()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get container image %q info from registry: %v", c.Image, err)
 	}
@@ -105,33 +182,123 @@ func getRemoteImage(pod *v1.Pod, namespace string, containerIndex int) (crv1.Ima
 	return img, nil
 }
 
-func getContainerCommand(pod *v1.Pod, namespace string, containerIndex int) ([]string, error) {
+// resolveImageFromIndex walks an OCI image index / Docker manifest list and
+// picks the manifest matching the trial pod's target architecture and OS, so
+// that getContainerCommand can inspect the right ConfigFile for multi-arch
+// images (e.g. python:3.11) instead of failing on the index itself.
+func resolveImageFromIndex(desc *remote.Descriptor, pod *v1.Pod, image string) (crv1.Image, error) {
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get image index for %q: %v", image, err)
+	}
+	idxManifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get index manifest for %q: %v", image, err)
+	}
+
+	wantOS, wantArch := podPlatform(pod)
+	for _, m := range idxManifest.Manifests {
+		if m.Platform == nil {
+			continue
+		}
+		if m.Platform.OS == wantOS && m.Platform.Architecture == wantArch {
+			img, err := idx.Image(m.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get image %s/%s for %q: %v", wantOS, wantArch, image, err)
+			}
+			return img, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Failed to find manifest for platform %s/%s in image index %q", wantOS, wantArch, image)
+}
+
+// podPlatform determines the OS/architecture that a trial pod's training
+// container is expected to run on, so that the right manifest can be picked
+// out of a multi-arch image index. It honors an explicit node selector or
+// node affinity on kubernetes.io/arch and kubernetes.io/os, and otherwise
+// falls back to "linux" and the architecture the katib-controller runs on.
+func podPlatform(pod *v1.Pod) (os string, arch string) {
+	os, arch = "linux", runtime.GOARCH
+
+	if v, ok := pod.Spec.NodeSelector[v1.LabelOSStable]; ok && v != "" {
+		os = v
+	}
+	if v, ok := pod.Spec.NodeSelector[v1.LabelArchStable]; ok && v != "" {
+		arch = v
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return os, arch
+	}
+
+	// NodeSelectorTerms are OR'd together: a pod only needs to satisfy one
+	// of them. If the terms disagree on the target arch/os (or only some of
+	// them constrain it), we cannot know which platform the pod will
+	// actually land on, so we leave the NodeSelector/default value in place
+	// instead of arbitrarily picking whichever term happened to be last.
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if v, ok := agreedSelectorValue(terms, v1.LabelOSStable); ok {
+		os = v
+	}
+	if v, ok := agreedSelectorValue(terms, v1.LabelArchStable); ok {
+		arch = v
+	}
+
+	return os, arch
+}
+
+// agreedSelectorValue returns the single value for key that every one of
+// terms agrees on via an "In" match expression. Because NodeSelectorTerms
+// are OR'd, a key is only resolvable to one value when all terms constrain
+// it identically; otherwise ok is false.
+func agreedSelectorValue(terms []v1.NodeSelectorTerm, key string) (value string, ok bool) {
+	for _, term := range terms {
+		termValue, termOK := "", false
+		for _, expr := range term.MatchExpressions {
+			if expr.Key != key || expr.Operator != v1.NodeSelectorOpIn || len(expr.Values) == 0 {
+				continue
+			}
+			termValue, termOK = expr.Values[0], true
+			break
+		}
+		if !termOK {
+			return "", false
+		}
+		if !ok {
+			value, ok = termValue, true
+			continue
+		}
+		if termValue != value {
+			return "", false
+		}
+	}
+	return value, ok
+}
+
+func getContainerCommand(pod *v1.Pod, namespace string, loc containerLocation) ([]string, error) {
 	// https://kubernetes.io/docs/tasks/inject-data-application/define-command-argument-container/#notes
-	var err error
-	var img crv1.Image
-	var cfg *crv1.ConfigFile
+	var resolved *ResolvedCommand
 	args := []string{}
-	c := pod.Spec.Containers[containerIndex]
+	c := *loc.get(pod)
 	if len(c.Command) != 0 {
 		args = append(args, c.Command...)
 	} else {
-		img, err = getRemoteImage(pod, namespace, containerIndex)
+		r, err := resolveContainerCommand(pod, namespace, loc)
 		if err != nil {
 			return nil, err
 		}
-		cfg, err = img.ConfigFile()
-		if err != nil {
-			return nil, fmt.Errorf("Failed to get config for image %q: %v", c.Image, err)
-		}
-		if len(cfg.Config.Entrypoint) != 0 {
-			args = append(args, cfg.Config.Entrypoint...)
+		resolved = r
+		if len(resolved.Entrypoint) != 0 {
+			args = append(args, resolved.Entrypoint...)
 		}
 	}
 	if len(c.Args) != 0 {
 		args = append(args, c.Args...)
 	} else {
-		if cfg != nil && len(cfg.Config.Cmd) != 0 {
-			args = append(args, cfg.Config.Cmd...)
+		if resolved != nil && len(resolved.Cmd) != 0 {
+			args = append(args, resolved.Cmd...)
 		}
 	}
 	return args, nil
@@ -179,50 +346,39 @@ func wrapWorkerContainer(
 	pod *v1.Pod, namespace, jobKind, metricsFile string,
 	pathKind common.FileSystemKind,
 	trial *trialsv1beta1.Trial) error {
-	index := -1
-	for i, c := range pod.Spec.Containers {
-		if trial.Spec.PrimaryContainerName != "" && c.Name == trial.Spec.PrimaryContainerName {
-			index = i
-			break
-			// TODO (andreyvelich): This can be deleted after switch to custom CRD
-		} else if trial.Spec.PrimaryContainerName == "" {
-			jobProvider, err := jobv1beta1.New(jobKind)
-			if err != nil {
-				return err
-			}
-			if jobProvider.IsTrainingContainer(i, c) {
-				index = i
-				break
-			}
-		}
+	loc, err := findPrimaryContainer(pod, jobKind, trial)
+	if err != nil {
+		return err
 	}
-	if index >= 0 {
-		command := []string{"sh", "-c"}
-		args, err := getContainerCommand(pod, namespace, index)
-		if err != nil {
-			return err
-		}
-		// If the first two commands are sh -c, we do not inject command.
-		if args[0] == "sh" || args[0] == "bash" {
-			if args[1] == "-c" {
-				command = args[0:2]
-				args = args[2:]
-			}
-		}
-		mc := trial.Spec.MetricsCollector
-		if mc.Collector.Kind == common.StdOutCollector {
-			redirectStr := fmt.Sprintf("1>%s 2>&1", metricsFile)
-			args = append(args, redirectStr)
+
+	if err := enforceTrustPolicy(pod, loc); err != nil {
+		return err
+	}
+	command := []string{"sh", "-c"}
+	args, err := getContainerCommand(pod, namespace, loc)
+	if err != nil {
+		return err
+	}
+	// If the first two commands are sh -c, we do not inject command.
+	if args[0] == "sh" || args[0] == "bash" {
+		if args[1] == "-c" {
+			command = args[0:2]
+			args = args[2:]
 		}
-		args = append(args, "&&", getMarkCompletedCommand(metricsFile, pathKind))
-		argsStr := strings.Join(args, " ")
-		c := &pod.Spec.Containers[index]
-		c.Command = command
-		c.Args = []string{argsStr}
-	} else {
-		return fmt.Errorf("Unable to find primary container %v in mutated pod containers %v",
-			trial.Spec.PrimaryContainerName, pod.Spec.Containers)
 	}
+	mc := trial.Spec.MetricsCollector
+	if mc.Collector.Kind == common.StdOutCollector {
+		redirectStr := fmt.Sprintf("1>%s 2>&1", metricsFile)
+		args = append(args, redirectStr)
+	}
+	// The mark-completed file path and command wrapping are identical for a
+	// regular training container and for an init-container-as-trainer (the
+	// native sidecar pattern), so this logic is not duplicated per code path.
+	args = append(args, "&&", getMarkCompletedCommand(metricsFile, pathKind))
+	argsStr := strings.Join(args, " ")
+	c := loc.get(pod)
+	c.Command = command
+	c.Args = []string{argsStr}
 	return nil
 }
 
@@ -236,7 +392,18 @@ func getMarkCompletedCommand(mountPath string, pathKind common.FileSystemKind) s
 	return fmt.Sprintf("echo %s > %s", mccommon.TrainingCompleted, pidFile)
 }
 
-func mutateVolume(pod *v1.Pod, jobKind, mountPath, sidecarContainerName string, pathKind common.FileSystemKind) error {
+// mutateVolume mounts the shared metrics volume into the sidecar container
+// and the training container(s). primaryContainerName is the trial's
+// Trial.Spec.PrimaryContainerName (may be empty) and, when it names an init
+// container, allows the native-sidecar training pattern (an init container
+// with restartPolicy: Always) to get the mount too.
+//
+// Collector kinds outside NeedWrapWorkerMetricsCollecterList never go
+// through wrapWorkerContainer, so this function also enforces the
+// TrustPolicy against every training container identified here — not just
+// the ones reachable through wrapWorkerContainer — before mounting the
+// metrics-collector volume into them.
+func mutateVolume(pod *v1.Pod, jobKind, mountPath, sidecarContainerName, primaryContainerName string, pathKind common.FileSystemKind) error {
 	metricsVol := v1.Volume{
 		Name: common.MetricsVolume,
 		VolumeSource: v1.VolumeSource{
@@ -251,35 +418,49 @@ func mutateVolume(pod *v1.Pod, jobKind, mountPath, sidecarContainerName string,
 		Name:      metricsVol.Name,
 		MountPath: dir,
 	}
-	indexList := []int{}
-	for i, c := range pod.Spec.Containers {
-		shouldMount := false
-		if c.Name == sidecarContainerName {
-			shouldMount = true
-		} else {
-			jobProvider, err := jobv1beta1.New(jobKind)
-			if err != nil {
+
+	jobProvider, err := jobv1beta1.New(jobKind)
+	if err != nil {
+		return err
+	}
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		isTraining := jobProvider.IsTrainingContainer(i, *c)
+		if isTraining {
+			if err := enforceTrustPolicy(pod, containerLocation{index: i}); err != nil {
 				return err
 			}
-			shouldMount = jobProvider.IsTrainingContainer(i, c)
 		}
-		if shouldMount {
-			indexList = append(indexList, i)
+		if c.Name == sidecarContainerName || isTraining {
+			mountMetricsVolume(c, vm)
 		}
 	}
-	for _, i := range indexList {
-		c := &pod.Spec.Containers[i]
-		if c.VolumeMounts == nil {
-			c.VolumeMounts = make([]v1.VolumeMount, 0)
+	for i := range pod.Spec.InitContainers {
+		c := &pod.Spec.InitContainers[i]
+		isTraining := (primaryContainerName != "" && c.Name == primaryContainerName) ||
+			(primaryContainerName == "" && jobProvider.IsTrainingContainer(i, *c))
+		if isTraining {
+			if err := enforceTrustPolicy(pod, containerLocation{index: i, init: true}); err != nil {
+				return err
+			}
+		}
+		if c.Name == sidecarContainerName || isTraining {
+			mountMetricsVolume(c, vm)
 		}
-		c.VolumeMounts = append(c.VolumeMounts, vm)
-		pod.Spec.Containers[i] = *c
 	}
+
 	pod.Spec.Volumes = append(pod.Spec.Volumes, metricsVol)
 
 	return nil
 }
 
+func mountMetricsVolume(c *v1.Container, vm v1.VolumeMount) {
+	if c.VolumeMounts == nil {
+		c.VolumeMounts = make([]v1.VolumeMount, 0)
+	}
+	c.VolumeMounts = append(c.VolumeMounts, vm)
+}
+
 func getSidecarContainerName(cKind common.CollectorKind) string {
 	if cKind == common.StdOutCollector || cKind == common.FileCollector {
 		return mccommon.MetricLoggerCollectorContainerName
@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"runtime"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	trialsv1beta1 "github.com/kubeflow/katib/pkg/apis/controller/trials/v1beta1"
+)
+
+func nodeSelectorTerm(os, arch string) v1.NodeSelectorTerm {
+	term := v1.NodeSelectorTerm{}
+	if os != "" {
+		term.MatchExpressions = append(term.MatchExpressions, v1.NodeSelectorRequirement{
+			Key: v1.LabelOSStable, Operator: v1.NodeSelectorOpIn, Values: []string{os},
+		})
+	}
+	if arch != "" {
+		term.MatchExpressions = append(term.MatchExpressions, v1.NodeSelectorRequirement{
+			Key: v1.LabelArchStable, Operator: v1.NodeSelectorOpIn, Values: []string{arch},
+		})
+	}
+	return term
+}
+
+func podWithAffinityTerms(terms ...v1.NodeSelectorTerm) *v1.Pod {
+	return &v1.Pod{
+		Spec: v1.PodSpec{
+			Affinity: &v1.Affinity{
+				NodeAffinity: &v1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+						NodeSelectorTerms: terms,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPodPlatform(t *testing.T) {
+	testCases := map[string]struct {
+		pod      *v1.Pod
+		wantOS   string
+		wantArch string
+	}{
+		"no selector or affinity falls back to linux/GOARCH": {
+			pod:      &v1.Pod{},
+			wantOS:   "linux",
+			wantArch: runtime.GOARCH,
+		},
+		"node selector wins": {
+			pod: &v1.Pod{
+				Spec: v1.PodSpec{
+					NodeSelector: map[string]string{
+						v1.LabelOSStable:   "linux",
+						v1.LabelArchStable: "arm64",
+					},
+				},
+			},
+			wantOS:   "linux",
+			wantArch: "arm64",
+		},
+		"single affinity term wins": {
+			pod:      podWithAffinityTerms(nodeSelectorTerm("linux", "arm64")),
+			wantOS:   "linux",
+			wantArch: "arm64",
+		},
+		"agreeing OR'd terms win": {
+			pod: podWithAffinityTerms(
+				nodeSelectorTerm("linux", "arm64"),
+				nodeSelectorTerm("linux", "arm64"),
+			),
+			wantOS:   "linux",
+			wantArch: "arm64",
+		},
+		"disagreeing OR'd terms fall back to default": {
+			pod: podWithAffinityTerms(
+				nodeSelectorTerm("linux", "arm64"),
+				nodeSelectorTerm("linux", "amd64"),
+			),
+			wantOS:   "linux",
+			wantArch: runtime.GOARCH,
+		},
+		"term not constraining arch falls back to default": {
+			pod: podWithAffinityTerms(
+				nodeSelectorTerm("linux", "arm64"),
+				nodeSelectorTerm("linux", ""),
+			),
+			wantOS:   "linux",
+			wantArch: runtime.GOARCH,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			os, arch := podPlatform(tc.pod)
+			if os != tc.wantOS || arch != tc.wantArch {
+				t.Errorf("podPlatform() = (%q, %q), want (%q, %q)", os, arch, tc.wantOS, tc.wantArch)
+			}
+		})
+	}
+}
+
+func TestFindPrimaryContainerByName(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "setup"}, {Name: "trainer-init"}},
+			Containers:     []v1.Container{{Name: "trainer"}, {Name: "metrics-logger"}},
+		},
+	}
+
+	testCases := map[string]struct {
+		primaryContainerName string
+		wantLoc              containerLocation
+	}{
+		"resolves a regular container by name": {
+			primaryContainerName: "trainer",
+			wantLoc:              containerLocation{index: 0},
+		},
+		"resolves an init container by name": {
+			primaryContainerName: "trainer-init",
+			wantLoc:              containerLocation{index: 1, init: true},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			trial := &trialsv1beta1.Trial{}
+			trial.Spec.PrimaryContainerName = tc.primaryContainerName
+
+			loc, err := findPrimaryContainer(pod, "", trial)
+			if err != nil {
+				t.Fatalf("findPrimaryContainer() returned error: %v", err)
+			}
+			if loc != tc.wantLoc {
+				t.Errorf("findPrimaryContainer() = %+v, want %+v", loc, tc.wantLoc)
+			}
+		})
+	}
+}
+
+func TestFindPrimaryContainerNotFound(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "trainer"}},
+		},
+	}
+	trial := &trialsv1beta1.Trial{}
+	trial.Spec.PrimaryContainerName = "does-not-exist"
+
+	if _, err := findPrimaryContainer(pod, "", trial); err == nil {
+		t.Fatal("findPrimaryContainer() returned no error for an unknown container name")
+	}
+}